@@ -0,0 +1,158 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// maxBundleSize limits the size of uploaded translation bundles
+const maxBundleSize = 1 << 20
+
+// registerAdminRoutes registers the admin API on mux, protected by the admin token
+func registerAdminRoutes(mux *http.ServeMux, token string, tr *translations, profile *profileSync) {
+	if token == "" {
+		log.Println("ADMIN_TOKEN not set, admin API disabled")
+		return
+	}
+
+	admin := func(h http.HandlerFunc) http.Handler {
+		return requireToken(token, h)
+	}
+
+	mux.Handle("GET /admin/translations", admin(handleListTranslations(tr)))
+	mux.Handle("GET /admin/translations/{locale}/missing", admin(handleMissingTranslations(tr)))
+	mux.Handle("PUT /admin/translations/{locale}", admin(handleUploadTranslations(tr)))
+	mux.Handle("POST /admin/translations/reload", admin(handleReloadTranslations(tr)))
+	mux.Handle("POST /admin/telegram/sync", admin(handleSyncProfile(profile)))
+}
+
+// requireToken rejects requests without a matching bearer token
+func requireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleListTranslations lists loaded locales with their missing keys
+func handleListTranslations(tr *translations) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		locales := make(map[string][]string)
+		for _, locale := range tr.Locales() {
+			locales[locale] = tr.Missing(locale)
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{
+			"default_locale": defaultLocale,
+			"missing":        locales,
+		})
+	}
+}
+
+// handleMissingTranslations lists the missing keys of a single locale
+func handleMissingTranslations(tr *translations) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		locale := r.PathValue("locale")
+		if !localePattern.MatchString(locale) {
+			writeError(w, http.StatusBadRequest, "invalid locale")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{
+			"locale":  locale,
+			"missing": tr.Missing(locale),
+		})
+	}
+}
+
+// handleUploadTranslations replaces the bundle of a locale and hot-reloads translations
+func handleUploadTranslations(tr *translations) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		locale := r.PathValue("locale")
+		if !localePattern.MatchString(locale) {
+			writeError(w, http.StatusBadRequest, "invalid locale")
+			return
+		}
+
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBundleSize))
+		if err != nil {
+			writeError(w, http.StatusRequestEntityTooLarge, "bundle too large")
+			return
+		}
+		bundle, err := parseBundle(data)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid bundle: "+err.Error())
+			return
+		}
+
+		if err := tr.Save(locale, bundle); err != nil {
+			if errors.Is(err, errNoLocalesDir) {
+				writeError(w, http.StatusConflict, "uploads disabled: "+err.Error())
+				return
+			}
+			log.Printf("Error saving translations for %s: %v", locale, err)
+			writeError(w, http.StatusInternalServerError, "error saving bundle")
+			return
+		}
+
+		log.Printf("Updated translations for %s (%d keys)", locale, len(bundle))
+		writeJSON(w, http.StatusOK, map[string]any{
+			"locale":  locale,
+			"missing": tr.Missing(locale),
+		})
+	}
+}
+
+// handleReloadTranslations reloads all bundles from disk
+func handleReloadTranslations(tr *translations) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := tr.Reload(); err != nil {
+			log.Printf("Error reloading translations: %v", err)
+			writeError(w, http.StatusInternalServerError, "error reloading translations")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{"locales": tr.Locales()})
+	}
+}
+
+// handleSyncProfile pushes the localized bot profile to Telegram
+func handleSyncProfile(profile *profileSync) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := profile.Sync(); err != nil {
+			if errors.Is(err, errBotNotReady) {
+				writeError(w, http.StatusServiceUnavailable, err.Error())
+				return
+			}
+			log.Printf("Error syncing bot profile: %v", err)
+			writeError(w, http.StatusBadGateway, "error syncing bot profile")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{"locales": profile.tr.Locales()})
+	}
+}
+
+// writeJSON writes v as a JSON response with the given status
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error writing response: %v", err)
+	}
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	defaultAlertCooldown  = 10 * time.Minute
+	defaultErrorThreshold = 20
+	defaultErrorWindow    = time.Minute
+	dependencyFailures    = 5
+	alertWebhookTimeout   = 10 * time.Second
+)
+
+// AlertsConfig configures where operator alerts are sent and when they fire
+type AlertsConfig struct {
+	ChatID         int64         `yaml:"chat_id"`
+	WebhookURL     string        `yaml:"webhook_url"`
+	Cooldown       time.Duration `yaml:"cooldown"`
+	ErrorThreshold int           `yaml:"error_threshold"`
+	ErrorWindow    time.Duration `yaml:"error_window"`
+}
+
+// alerter sends operator alerts to the admin chat and webhook, suppressing
+// repeats of the same alert until its cooldown has passed
+type alerter struct {
+	cfg    AlertsConfig
+	client *http.Client
+
+	mu       sync.Mutex
+	api      *tgbotapi.BotAPI
+	lastSent map[string]time.Time
+	errors   []time.Time
+	failures map[string]int
+}
+
+// newAlerter creates an alerter, filling in defaults for unset limits
+func newAlerter(cfg AlertsConfig) *alerter {
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = defaultAlertCooldown
+	}
+	if cfg.ErrorThreshold <= 0 {
+		cfg.ErrorThreshold = defaultErrorThreshold
+	}
+	if cfg.ErrorWindow <= 0 {
+		cfg.ErrorWindow = defaultErrorWindow
+	}
+
+	return &alerter{
+		cfg:      cfg,
+		client:   &http.Client{Timeout: alertWebhookTimeout},
+		lastSent: make(map[string]time.Time),
+		failures: make(map[string]int),
+	}
+}
+
+// SetBot sets the bot used to deliver alerts to the admin chat
+func (a *alerter) SetBot(api *tgbotapi.BotAPI) {
+	a.mu.Lock()
+	a.api = api
+	a.mu.Unlock()
+}
+
+// Alert notifies operators, unless an alert with the same key was sent within the cooldown
+func (a *alerter) Alert(key, text string) {
+	a.mu.Lock()
+	if last, ok := a.lastSent[key]; ok && time.Since(last) < a.cfg.Cooldown {
+		a.mu.Unlock()
+		return
+	}
+	a.lastSent[key] = time.Now()
+	api := a.api
+	a.mu.Unlock()
+
+	log.Printf("ALERT [%s] %s", key, text)
+	go a.deliver(api, key, text)
+}
+
+// RecordError counts an error from source and alerts when the error rate spikes
+func (a *alerter) RecordError(source string) {
+	now := time.Now()
+
+	a.mu.Lock()
+	cutoff := now.Add(-a.cfg.ErrorWindow)
+	kept := a.errors[:0]
+	for _, t := range a.errors {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	a.errors = append(kept, now)
+	count := len(a.errors)
+	a.mu.Unlock()
+
+	if count >= a.cfg.ErrorThreshold {
+		a.Alert("error_rate", fmt.Sprintf("%d errors in the last %s (latest from %s)", count, a.cfg.ErrorWindow, source))
+	}
+}
+
+// RecordDependency tracks the result of a call to a dependency and alerts
+// once it has failed several times in a row
+func (a *alerter) RecordDependency(name string, err error) {
+	a.mu.Lock()
+	if err == nil {
+		recovered := a.failures[name] >= dependencyFailures
+		a.failures[name] = 0
+		a.mu.Unlock()
+		if recovered {
+			log.Printf("Dependency %s reachable again", name)
+		}
+		return
+	}
+	a.failures[name]++
+	failures := a.failures[name]
+	a.mu.Unlock()
+
+	a.RecordError(name)
+	if failures >= dependencyFailures {
+		a.Alert(name+"_unreachable", fmt.Sprintf("Dependency %s failed %d times in a row: %v", name, failures, err))
+	}
+}
+
+// deliver sends an alert to every configured channel
+func (a *alerter) deliver(api *tgbotapi.BotAPI, key, text string) {
+	if a.cfg.ChatID != 0 && api != nil {
+		msg := tgbotapi.NewMessage(a.cfg.ChatID, fmt.Sprintf("⚠️ [%s] %s", key, text))
+		if _, err := api.Send(msg); err != nil {
+			log.Printf("Error sending alert to admin chat: %v", err)
+		}
+	}
+
+	if a.cfg.WebhookURL != "" {
+		body, _ := json.Marshal(map[string]any{
+			"key":  key,
+			"text": text,
+			"time": time.Now().UTC(),
+		})
+		resp, err := a.client.Post(a.cfg.WebhookURL, "application/json", bytes.NewReader(body))
+		if err != nil {
+			log.Printf("Error sending alert webhook: %v", err)
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			log.Printf("Alert webhook returned %s", resp.Status)
+		}
+	}
+}
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// alertOnErrors records 5xx responses of next as errors; 503 is skipped since
+// handlers return it deliberately, e.g. /healthz while starting
+func alertOnErrors(a *alerter, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		if rec.status >= http.StatusInternalServerError && rec.status != http.StatusServiceUnavailable {
+			a.RecordError(r.Method + " " + r.URL.Path)
+		}
+	})
+}
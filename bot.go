@@ -0,0 +1,384 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// broadcastInterval spaces out broadcast messages to stay under Telegram rate limits
+const broadcastInterval = 50 * time.Millisecond
+
+// botHandler processes Telegram updates and keeps the in-memory bot state
+type botHandler struct {
+	api     *tgbotapi.BotAPI
+	config  Config
+	render  *renderer
+	tr      *translations
+	flags   *featureFlags
+	alerts  *alerter
+	link    *gameLink
+	admins  map[int64]bool
+	started time.Time
+
+	// stopping is closed by Stop; Run closes drained once the buffered updates are handled
+	stopping chan struct{}
+	drained  chan struct{}
+
+	// work tracks broadcasts in progress so Stop can wait for them
+	work      sync.Mutex
+	inflight  int
+	stopped   bool
+	idle      chan struct{}
+	remaining atomic.Int64
+
+	mu        sync.Mutex
+	updates   int
+	users     map[int64]bool
+	chats     map[int64]bool
+	banned    map[int64]bool
+	usernames map[string]int64
+}
+
+// newBotHandler creates a handler for updates received by api
+func newBotHandler(api *tgbotapi.BotAPI, config Config, render *renderer, tr *translations, flags *featureFlags, alerts *alerter, link *gameLink) *botHandler {
+	admins := make(map[int64]bool, len(config.AdminUserIDs))
+	for _, id := range config.AdminUserIDs {
+		admins[id] = true
+	}
+
+	return &botHandler{
+		api:       api,
+		config:    config,
+		render:    render,
+		tr:        tr,
+		flags:     flags,
+		alerts:    alerts,
+		link:      link,
+		admins:    admins,
+		started:   time.Now(),
+		stopping:  make(chan struct{}),
+		drained:   make(chan struct{}),
+		idle:      make(chan struct{}),
+		users:     make(map[int64]bool),
+		chats:     make(map[int64]bool),
+		banned:    make(map[int64]bool),
+		usernames: make(map[string]int64),
+	}
+}
+
+// Run handles updates until the channel is closed or Stop is called, then
+// handles the updates still buffered in the channel
+func (b *botHandler) Run(updates tgbotapi.UpdatesChannel) {
+	defer close(b.drained)
+
+	for {
+		select {
+		case update, ok := <-updates:
+			if !ok {
+				return
+			}
+			b.safeHandleUpdate(update)
+		case <-b.stopping:
+			for {
+				select {
+				case update, ok := <-updates:
+					if !ok {
+						return
+					}
+					b.safeHandleUpdate(update)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// Stop stops receiving updates and waits until the updates already buffered
+// and any running broadcast are handled or ctx is done. The library confirms
+// a batch of updates with the following long poll, so buffered updates are
+// handled rather than dropped; updates the library has not buffered yet, such
+// as those returned by the long poll pending at Stop, stay unconfirmed and
+// Telegram delivers them again on the next start.
+func (b *botHandler) Stop(ctx context.Context) error {
+	b.api.StopReceivingUpdates()
+
+	b.work.Lock()
+	b.stopped = true
+	if b.inflight == 0 {
+		close(b.idle)
+	}
+	b.work.Unlock()
+	close(b.stopping)
+
+	select {
+	case <-b.drained:
+	case <-ctx.Done():
+		return fmt.Errorf("%v, buffered updates not handled", ctx.Err())
+	}
+
+	select {
+	case <-b.idle:
+		return nil
+	case <-ctx.Done():
+		if n := b.remaining.Load(); n > 0 {
+			return fmt.Errorf("%v, %d broadcast messages not sent", ctx.Err(), n)
+		}
+		return ctx.Err()
+	}
+}
+
+// begin registers a broadcast in progress, reporting false once Stop has been called
+func (b *botHandler) begin() bool {
+	b.work.Lock()
+	defer b.work.Unlock()
+
+	if b.stopped {
+		return false
+	}
+	b.inflight++
+
+	return true
+}
+
+// end marks a broadcast registered with begin as finished
+func (b *botHandler) end() {
+	b.work.Lock()
+	defer b.work.Unlock()
+
+	b.inflight--
+	if b.stopped && b.inflight == 0 {
+		close(b.idle)
+	}
+}
+
+// safeHandleUpdate processes a single update, recovering from panics so one
+// bad update does not stop the update loop
+func (b *botHandler) safeHandleUpdate(update tgbotapi.Update) {
+	defer func() {
+		if p := recover(); p != nil {
+			logPanic(fmt.Sprintf("update %d", update.UpdateID), p)
+			b.alerts.RecordError("bot update")
+		}
+	}()
+
+	b.handleUpdate(update)
+}
+
+// handleUpdate processes a single update
+func (b *botHandler) handleUpdate(update tgbotapi.Update) {
+	if update.Message == nil || update.Message.From == nil {
+		return
+	}
+	message := update.Message
+	if !b.track(message) {
+		return
+	}
+	if !message.IsCommand() {
+		return
+	}
+
+	locale := message.From.LanguageCode
+	msg := b.render.NewMessage(message.Chat.ID, "")
+
+	command := message.Command()
+	if b.admins[message.From.ID] {
+		if text, ok := b.handleAdminCommand(command, message.CommandArguments()); ok {
+			msg.Text = b.render.Text("", text)
+			b.send(msg)
+			return
+		}
+	}
+
+	switch command {
+	case "start":
+		msg.Text = b.render.Text("game", b.tr.T(locale, "start.welcome"))
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonURL(b.tr.T(locale, "start.play_button"), b.link.URL()),
+			),
+		)
+	default:
+		msg.Text = b.render.Text("", b.tr.T(locale, "command.unknown"))
+	}
+	b.send(msg)
+}
+
+// send sends a message, reporting Telegram API outages to the alerter
+func (b *botHandler) send(msg tgbotapi.Chattable) error {
+	_, err := b.api.Send(msg)
+	b.alerts.RecordDependency("telegram", telegramOutage(err))
+	if err != nil {
+		log.Printf("Error sending message: %v", err)
+	}
+
+	return err
+}
+
+// telegramOutage returns err if it means the Telegram API is unavailable
+// (transport errors, 5xx and 429 replies) and nil otherwise; other API
+// errors, such as 403 from users who blocked the bot, show Telegram is reachable
+func telegramOutage(err error) error {
+	var apiErr *tgbotapi.Error
+	if err == nil || !errors.As(err, &apiErr) {
+		return err
+	}
+	if apiErr.Code == http.StatusTooManyRequests || apiErr.Code >= http.StatusInternalServerError {
+		return err
+	}
+
+	return nil
+}
+
+// telegramAuthError reports whether err means Telegram rejected the bot token
+func telegramAuthError(err error) bool {
+	var apiErr *tgbotapi.Error
+	return errors.As(err, &apiErr) && (apiErr.Code == http.StatusUnauthorized || apiErr.Code == http.StatusNotFound)
+}
+
+// track records the sender and chat of message, reporting false if the sender is banned
+func (b *botHandler) track(message *tgbotapi.Message) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.updates++
+	if b.banned[message.From.ID] {
+		return false
+	}
+
+	b.users[message.From.ID] = true
+	if message.From.UserName != "" {
+		b.usernames[strings.ToLower(message.From.UserName)] = message.From.ID
+	}
+	if message.Chat.IsPrivate() {
+		b.chats[message.Chat.ID] = true
+	}
+
+	return true
+}
+
+// handleAdminCommand runs an admin-only command, reporting false if command is not one
+func (b *botHandler) handleAdminCommand(command, args string) (string, bool) {
+	switch command {
+	case "stats":
+		return b.stats(), true
+	case "ban":
+		return b.ban(args), true
+	case "broadcast":
+		return b.broadcast(args), true
+	case "flag":
+		return b.flag(args), true
+	}
+
+	return "", false
+}
+
+// stats summarizes the bot state since startup
+func (b *botHandler) stats() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return fmt.Sprintf(
+		"Uptime: %s\nUpdates: %d\nUsers: %d\nPrivate chats: %d\nBanned: %d",
+		time.Since(b.started).Round(time.Second), b.updates, len(b.users), len(b.chats), len(b.banned),
+	)
+}
+
+// ban blocks a user, given by numeric ID or by username of a user the bot has seen
+func (b *botHandler) ban(args string) string {
+	target := strings.TrimPrefix(strings.TrimSpace(args), "@")
+	if target == "" {
+		return "Usage: /ban <user id or @username>"
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id, err := strconv.ParseInt(target, 10, 64)
+	if err != nil {
+		var ok bool
+		if id, ok = b.usernames[strings.ToLower(target)]; !ok {
+			return fmt.Sprintf("Unknown user @%s", target)
+		}
+	}
+	if b.admins[id] {
+		return "Admins cannot be banned"
+	}
+
+	b.banned[id] = true
+	delete(b.chats, id)
+	log.Printf("Banned user %d", id)
+
+	return fmt.Sprintf("Banned user %d", id)
+}
+
+// broadcast sends text to every private chat the bot has seen
+func (b *botHandler) broadcast(text string) string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "Usage: /broadcast <text>"
+	}
+
+	b.mu.Lock()
+	chats := make([]int64, 0, len(b.chats))
+	for id := range b.chats {
+		chats = append(chats, id)
+	}
+	b.mu.Unlock()
+
+	// Registered before starting the goroutine so Stop cannot miss the broadcast
+	if !b.begin() {
+		return "Shutting down, broadcast not sent"
+	}
+	b.remaining.Add(int64(len(chats)))
+	go func() {
+		defer b.end()
+
+		failed := 0
+		for _, id := range chats {
+			if err := b.send(b.render.NewMessage(id, b.render.Text("", text))); err != nil {
+				failed++
+			}
+			b.remaining.Add(-1)
+			time.Sleep(broadcastInterval)
+		}
+		log.Printf("Broadcast sent to %d chats, %d failed", len(chats)-failed, failed)
+	}()
+
+	return fmt.Sprintf("Broadcasting to %d chats", len(chats))
+}
+
+// flag toggles a feature flag, or lists flags when called without arguments
+func (b *botHandler) flag(args string) string {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		var lines []string
+		for _, name := range b.flags.Names() {
+			lines = append(lines, fmt.Sprintf("%s: %t", name, b.flags.Enabled(name)))
+		}
+		if len(lines) == 0 {
+			return "No feature flags"
+		}
+		return strings.Join(lines, "\n")
+	}
+	if len(fields) != 2 || (fields[1] != "on" && fields[1] != "off") {
+		return "Usage: /flag <feature> on|off"
+	}
+
+	if !b.flags.Set(fields[0], fields[1] == "on") {
+		return fmt.Sprintf("Unknown feature %s", fields[0])
+	}
+	log.Printf("Feature %s turned %s", fields[0], fields[1])
+
+	return fmt.Sprintf("Feature %s is %s", fields[0], fields[1])
+}
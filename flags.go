@@ -0,0 +1,57 @@
+package main
+
+import (
+	"sort"
+	"sync"
+)
+
+// featureFlags holds runtime toggles for known features
+type featureFlags struct {
+	mu    sync.RWMutex
+	flags map[string]bool
+}
+
+// newFeatureFlags creates feature flags with the given initial values
+func newFeatureFlags(initial map[string]bool) *featureFlags {
+	flags := make(map[string]bool, len(initial))
+	for name, enabled := range initial {
+		flags[name] = enabled
+	}
+
+	return &featureFlags{flags: flags}
+}
+
+// Enabled reports whether the named feature is enabled
+func (f *featureFlags) Enabled(name string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.flags[name]
+}
+
+// Set toggles a known feature, reporting false if the feature is unknown
+func (f *featureFlags) Set(name string, enabled bool) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.flags[name]; !ok {
+		return false
+	}
+	f.flags[name] = enabled
+
+	return true
+}
+
+// Names returns the sorted names of all known features
+func (f *featureFlags) Names() []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	names := make([]string, 0, len(f.flags))
+	for name := range f.flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// GamePageConfig controls how /game sends players to the game
+type GamePageConfig struct {
+	Interstitial bool   `yaml:"interstitial"`
+	Title        string `yaml:"title"`
+}
+
+// interstitialTemplate embeds the game in a full-page frame with a direct link as fallback
+var interstitialTemplate = template.Must(template.New("game").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>html, body, iframe { margin: 0; width: 100%; height: 100%; border: 0; }</style>
+</head>
+<body>
+<iframe src="{{.URL}}" allow="fullscreen; autoplay" title="{{.Title}}"></iframe>
+<noscript><a href="{{.URL}}">{{.Title}}</a></noscript>
+</body>
+</html>
+`))
+
+// gameLink holds the current game URL, which build webhooks can update at runtime
+type gameLink struct {
+	mu  sync.RWMutex
+	url string
+}
+
+// newGameLink creates a game link pointing at rawURL
+func newGameLink(rawURL string) *gameLink {
+	return &gameLink{url: rawURL}
+}
+
+// URL returns the current game URL
+func (l *gameLink) URL() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.url
+}
+
+// SetURL points the game link at rawURL
+func (l *gameLink) SetURL(rawURL string) {
+	l.mu.Lock()
+	l.url = rawURL
+	l.mu.Unlock()
+}
+
+// gameHandler sends players to the hosted game
+type gameHandler struct {
+	shortName string
+	link      *gameLink
+	page      GamePageConfig
+}
+
+// handleGame redirects to the game, or serves an interstitial page embedding it,
+// passing through the request's query parameters (e.g. UTM tags)
+func (g *gameHandler) handleGame(w http.ResponseWriter, r *http.Request) {
+	if name := r.PathValue("shortname"); name != "" && name != g.shortName {
+		http.NotFound(w, r)
+		return
+	}
+
+	target, err := g.targetURL(r.URL.Query())
+	if err != nil {
+		log.Printf("Error building game URL: %v", err)
+		writeError(w, http.StatusInternalServerError, "invalid game URL")
+		return
+	}
+
+	if !g.page.Interstitial {
+		http.Redirect(w, r, target, http.StatusFound)
+		return
+	}
+
+	title := g.page.Title
+	if title == "" {
+		title = g.shortName
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := interstitialTemplate.Execute(w, map[string]string{"Title": title, "URL": target}); err != nil {
+		log.Printf("Error rendering game page: %v", err)
+	}
+}
+
+// targetURL returns the game URL with query added; parameters already set in the game URL win
+func (g *gameHandler) targetURL(query url.Values) (string, error) {
+	u, err := url.Parse(g.link.URL())
+	if err != nil {
+		return "", err
+	}
+
+	values := u.Query()
+	for key, vals := range query {
+		if _, ok := values[key]; !ok {
+			values[key] = vals
+		}
+	}
+	u.RawQuery = values.Encode()
+
+	return u.String(), nil
+}
@@ -3,6 +3,6 @@ module github.com/vinatorul/telegame-backend
 go 1.23.2
 
 require (
-	github.com/go-telegram-bot-api/telegram-bot-api/v5 v5.5.1 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	github.com/go-telegram-bot-api/telegram-bot-api/v5 v5.5.1
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// maxRetryBackoff caps the delay between startup attempts
+const maxRetryBackoff = 30 * time.Second
+
+// permanentError marks an error that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+// health tracks whether the service has finished starting up
+type health struct {
+	ready atomic.Bool
+}
+
+// SetReady marks all dependencies as ready
+func (h *health) SetReady() {
+	h.ready.Store(true)
+	log.Println("All dependencies ready")
+}
+
+// handleHealthz reports "starting" until dependencies are ready and "ok" afterwards
+func (h *health) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	if !h.ready.Load() {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "starting"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// retry calls check with exponential backoff until it succeeds, returns a
+// permanentError or has been tried attempts times (forever if attempts is 0),
+// logging each failure
+func retry(name string, attempts int, check func() error) error {
+	backoff := time.Second
+	for attempt := 1; ; attempt++ {
+		err := check()
+		if err == nil {
+			log.Printf("%s reachable", name)
+			return nil
+		}
+
+		var permanent permanentError
+		if errors.As(err, &permanent) {
+			return fmt.Errorf("%s rejected the request: %v", name, permanent.err)
+		}
+		if attempts > 0 && attempt >= attempts {
+			return fmt.Errorf("%s unreachable after %d attempts: %v", name, attempts, err)
+		}
+
+		log.Printf("%s unreachable (attempt %d, retrying in %s): %v", name, attempt, backoff, err)
+		time.Sleep(backoff)
+		backoff = min(backoff*2, maxRetryBackoff)
+	}
+}
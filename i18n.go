@@ -0,0 +1,238 @@
+package main
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultLocale is the locale every other bundle is compared against
+const defaultLocale = "en"
+
+//go:embed locales/*.yaml
+var builtinLocales embed.FS
+
+// localePattern matches the locale codes accepted for translation bundles
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})?$`)
+
+// errNoLocalesDir is returned when saving a bundle without a locales directory
+var errNoLocalesDir = errors.New("locales_dir not configured")
+
+// translations holds the translation bundles keyed by locale
+type translations struct {
+	dir string
+
+	// fileMu serializes Save and Reload so concurrent uploads cannot interleave
+	// and a reload cannot store a snapshot older than a concurrent save
+	fileMu sync.Mutex
+
+	mu      sync.RWMutex
+	bundles map[string]map[string]string
+}
+
+// newTranslations creates translations from the built-in bundles, overridden
+// by the bundles in dir unless dir is empty
+func newTranslations(dir string) (*translations, error) {
+	t := &translations{dir: dir}
+	if err := t.Reload(); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Reload rebuilds all bundles from the built-in locales and the locales directory
+func (t *translations) Reload() error {
+	t.fileMu.Lock()
+	defer t.fileMu.Unlock()
+
+	return t.reload()
+}
+
+// reload rebuilds all bundles; the caller must hold fileMu
+func (t *translations) reload() error {
+	bundles := make(map[string]map[string]string)
+
+	builtin, err := builtinLocales.ReadDir("locales")
+	if err != nil {
+		return fmt.Errorf("error reading built-in locales: %v", err)
+	}
+	for _, entry := range builtin {
+		data, err := builtinLocales.ReadFile("locales/" + entry.Name())
+		if err != nil {
+			return fmt.Errorf("error reading built-in locale %s: %v", entry.Name(), err)
+		}
+		if err := mergeBundle(bundles, entry.Name(), data); err != nil {
+			return err
+		}
+	}
+
+	if t.dir != "" {
+		files, err := filepath.Glob(filepath.Join(t.dir, "*.yaml"))
+		if err != nil {
+			return fmt.Errorf("error listing locales: %v", err)
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("error reading locale %s: %v", file, err)
+			}
+			if err := mergeBundle(bundles, filepath.Base(file), data); err != nil {
+				return err
+			}
+		}
+	}
+
+	t.mu.Lock()
+	t.bundles = bundles
+	t.mu.Unlock()
+
+	log.Printf("Loaded translations for %d locales", len(bundles))
+	return nil
+}
+
+// mergeBundle parses a bundle file and merges it into bundles under its
+// lowercased locale, skipping files not named after a valid locale
+func mergeBundle(bundles map[string]map[string]string, name string, data []byte) error {
+	locale := strings.ToLower(strings.TrimSuffix(name, ".yaml"))
+	if !localePattern.MatchString(locale) {
+		log.Printf("Skipping translation bundle %s: not a valid locale name", name)
+		return nil
+	}
+
+	bundle, err := parseBundle(data)
+	if err != nil {
+		return fmt.Errorf("error parsing locale %s: %v", name, err)
+	}
+
+	if bundles[locale] == nil {
+		bundles[locale] = make(map[string]string)
+	}
+	for key, value := range bundle {
+		bundles[locale][key] = value
+	}
+
+	return nil
+}
+
+// parseBundle parses a flat key/value bundle in YAML or JSON
+func parseBundle(data []byte) (map[string]string, error) {
+	bundle := make(map[string]string)
+	if err := yaml.Unmarshal(data, &bundle); err != nil {
+		return nil, err
+	}
+
+	return bundle, nil
+}
+
+// Save replaces the bundle for locale, writes it to the locales directory and reloads
+func (t *translations) Save(locale string, bundle map[string]string) error {
+	if t.dir == "" {
+		return errNoLocalesDir
+	}
+
+	t.fileMu.Lock()
+	defer t.fileMu.Unlock()
+
+	data, err := yaml.Marshal(bundle)
+	if err != nil {
+		return fmt.Errorf("error encoding bundle: %v", err)
+	}
+	if err := os.MkdirAll(t.dir, 0o755); err != nil {
+		return fmt.Errorf("error creating locales directory: %v", err)
+	}
+
+	// Write to a temporary file first so a concurrent reload never sees a partial bundle
+	tmp, err := os.CreateTemp(t.dir, locale+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error writing bundle: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing bundle: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing bundle: %v", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("error writing bundle: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(t.dir, locale+".yaml")); err != nil {
+		return fmt.Errorf("error writing bundle: %v", err)
+	}
+
+	return t.reload()
+}
+
+// T returns the translation of key for locale, falling back to the base
+// language, then the default locale, then the key itself
+func (t *translations) T(locale, key string) string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	locale = strings.ToLower(locale)
+	candidates := []string{locale}
+	if base, _, ok := strings.Cut(locale, "-"); ok {
+		candidates = append(candidates, base)
+	}
+	candidates = append(candidates, defaultLocale)
+
+	for _, candidate := range candidates {
+		if value, ok := t.bundles[candidate][key]; ok && value != "" {
+			return value
+		}
+	}
+
+	return key
+}
+
+// Lookup returns the translation of key in locale only, without any fallback
+func (t *translations) Lookup(locale, key string) (string, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	value, ok := t.bundles[locale][key]
+	return value, ok && value != ""
+}
+
+// Locales returns the sorted list of loaded locales
+func (t *translations) Locales() []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	locales := make([]string, 0, len(t.bundles))
+	for locale := range t.bundles {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+
+	return locales
+}
+
+// Missing returns the sorted keys present in the default locale but missing or empty in locale
+func (t *translations) Missing(locale string) []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	missing := []string{}
+	bundle := t.bundles[locale]
+	for key := range t.bundles[defaultLocale] {
+		if bundle[key] == "" {
+			missing = append(missing, key)
+		}
+	}
+	sort.Strings(missing)
+
+	return missing
+}
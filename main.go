@@ -1,23 +1,45 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"syscall"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"gopkg.in/yaml.v3"
 )
 
+// defaultShutdownTimeout is used when shutdown_timeout is not configured; it stays
+// below Docker's default 10s stop grace period
+const defaultShutdownTimeout = 8 * time.Second
+
 // Config holds the application configuration
 type Config struct {
-	TelegramToken string `yaml:"telegram_token"`
-	GameShortName string `yaml:"game_short_name"`
-	Port          string `yaml:"port"`
-	GameURL       string `yaml:"game_url"`
+	TelegramToken   string        `yaml:"telegram_token"`
+	GameShortName   string        `yaml:"game_short_name"`
+	Port            string        `yaml:"port"`
+	GameURL         string        `yaml:"game_url"`
+	AdminToken      string        `yaml:"admin_token"`
+	LocalesDir      string        `yaml:"locales_dir"`
+	AdminUserIDs    []int64       `yaml:"admin_user_ids"`
+	StartupRetries  int           `yaml:"startup_retries"`
+	SyncProfile     bool          `yaml:"sync_profile"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+
+	Features map[string]bool `yaml:"features"`
+
+	CustomEmoji CustomEmojiConfig  `yaml:"custom_emoji"`
+	Alerts      AlertsConfig       `yaml:"alerts"`
+	GamePage    GamePageConfig     `yaml:"game_page"`
+	BuildHook   BuildWebhookConfig `yaml:"build_webhook"`
 }
 
 // loadConfig reads and parses the configuration from config.yaml
@@ -55,6 +77,28 @@ func main() {
 			GameShortName: os.Getenv("GAME_SHORT_NAME"),
 			Port:          os.Getenv("PORT"),
 			GameURL:       os.Getenv("GAME_URL"),
+			AdminToken:    os.Getenv("ADMIN_TOKEN"),
+			LocalesDir:    os.Getenv("LOCALES_DIR"),
+			AdminUserIDs:  parseIDs(os.Getenv("ADMIN_USER_IDS")),
+
+			StartupRetries:  int(envInt("STARTUP_RETRIES")),
+			ShutdownTimeout: envDuration("SHUTDOWN_TIMEOUT"),
+			SyncProfile:     envBool("SYNC_PROFILE"),
+
+			CustomEmoji: CustomEmojiConfig{Enabled: envBool("CUSTOM_EMOJI_ENABLED")},
+			Alerts: AlertsConfig{
+				ChatID:     envInt("ALERTS_CHAT_ID"),
+				WebhookURL: os.Getenv("ALERTS_WEBHOOK_URL"),
+			},
+			GamePage: GamePageConfig{
+				Interstitial: envBool("GAME_PAGE_INTERSTITIAL"),
+				Title:        os.Getenv("GAME_PAGE_TITLE"),
+			},
+			BuildHook: BuildWebhookConfig{
+				Secret:         os.Getenv("BUILD_WEBHOOK_SECRET"),
+				AnnounceChatID: envInt("BUILD_WEBHOOK_ANNOUNCE_CHAT_ID"),
+				StateFile:      os.Getenv("BUILD_WEBHOOK_STATE_FILE"),
+			},
 		}
 		if config.Port == "" {
 			config.Port = "8080"
@@ -64,55 +108,59 @@ func main() {
 		}
 	}
 
-	var bot *tgbotapi.BotAPI
+	if config.StartupRetries < 0 {
+		config.StartupRetries = 0
+	}
+	if config.ShutdownTimeout <= 0 {
+		config.ShutdownTimeout = defaultShutdownTimeout
+	}
 
-	if config.TelegramToken != "" {
-		bot, err = tgbotapi.NewBotAPI(config.TelegramToken)
-		if err != nil {
-			log.Printf("Error initializing Telegram bot: %v", err)
-		} else {
-			log.Printf("Authorized on account %s", bot.Self.UserName)
-
-			// Start polling for updates
-			u := tgbotapi.NewUpdate(0)
-			u.Timeout = 60
-			updates := bot.GetUpdatesChan(u)
-
-			// Handle updates in a goroutine
-			go func() {
-				for update := range updates {
-					if update.Message != nil && update.Message.IsCommand() {
-						msg := tgbotapi.NewMessage(update.Message.Chat.ID, "")
-						switch update.Message.Command() {
-						case "start":
-							msg.Text = "Welcome to the Telegram game bot!"
-							msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
-								tgbotapi.NewInlineKeyboardRow(
-									tgbotapi.NewInlineKeyboardButtonURL("Play now", config.GameURL),
-								),
-							)
-						default:
-							msg.Text = "Unknown command"
-						}
-						bot.Send(msg)
-					}
-				}
-			}()
-		}
-	} else {
-		log.Println("TELEGRAM_TOKEN not set, bot functionality disabled")
+	tr, err := newTranslations(config.LocalesDir)
+	if err != nil {
+		log.Fatalf("Error loading translations: %v", err)
+	}
+
+	features := map[string]bool{customEmojiFeature: config.CustomEmoji.Enabled}
+	for name, enabled := range config.Features {
+		features[name] = enabled
 	}
+	flags := newFeatureFlags(features)
+
+	render := newRenderer(config.CustomEmoji, flags)
+	status := &health{}
+	profile := newProfileSync(tr)
+	alerts := newAlerter(config.Alerts)
+	link := newGameLink(config.GameURL)
+	builds, err := newBuildWebhook(config.BuildHook, config.GameShortName, link)
+	if err != nil {
+		log.Fatalf("Error loading build webhook: %v", err)
+	}
+	var handler atomic.Pointer[botHandler]
+	fatal := make(chan error, 1)
 
 	// Set up HTTP server
 	mux := http.NewServeMux()
 
 	// Register routes
 	mux.HandleFunc("/", handleRoot)
+	mux.HandleFunc("GET /healthz", status.handleHealthz)
+	game := &gameHandler{shortName: config.GameShortName, link: link, page: config.GamePage}
+	mux.HandleFunc("GET /game", game.handleGame)
+	mux.HandleFunc("GET /game/{shortname}", game.handleGame)
+	registerAdminRoutes(mux, config.AdminToken, tr, profile)
+	switch {
+	case config.BuildHook.Secret == "":
+		log.Println("build_webhook.secret not set, build webhook disabled")
+	case config.GameShortName == "":
+		log.Println("game_short_name not set, build webhook disabled")
+	default:
+		mux.HandleFunc("POST /webhooks/build", builds.handleBuild)
+	}
 
 	// Create server
 	server := &http.Server{
 		Addr:    ":" + config.Port,
-		Handler: mux,
+		Handler: alertOnErrors(alerts, recoverHandler(mux)),
 	}
 
 	// Start server in a goroutine
@@ -123,12 +171,148 @@ func main() {
 		}
 	}()
 
+	// Connect to dependencies in the background; /healthz reports "starting" until done
+	go func() {
+		if config.TelegramToken == "" {
+			log.Println("TELEGRAM_TOKEN not set, bot functionality disabled")
+			status.SetReady()
+			return
+		}
+
+		var bot *tgbotapi.BotAPI
+		err := retry("Telegram API", config.StartupRetries, func() error {
+			var err error
+			bot, err = tgbotapi.NewBotAPI(config.TelegramToken)
+			if telegramAuthError(err) {
+				return permanentError{fmt.Errorf("invalid telegram_token: %v", err)}
+			}
+			return err
+		})
+		if err != nil {
+			fatal <- fmt.Errorf("error initializing Telegram bot: %v", err)
+			return
+		}
+		log.Printf("Authorized on account %s", bot.Self.UserName)
+
+		// Start polling for updates
+		u := tgbotapi.NewUpdate(0)
+		u.Timeout = 60
+		updates := bot.GetUpdatesChan(u)
+
+		// Handle updates in a goroutine
+		h := newBotHandler(bot, config, render, tr, flags, alerts, link)
+		handler.Store(h)
+		go h.Run(updates)
+
+		profile.SetBot(bot)
+		alerts.SetBot(bot)
+		builds.SetBot(bot)
+		if config.SyncProfile {
+			if err := profile.Sync(); err != nil {
+				log.Printf("Error syncing bot profile: %v", err)
+			}
+		}
+
+		status.SetReady()
+	}()
+
 	// Set up graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+
+	exitCode := 0
+	select {
+	case <-quit:
+	case err := <-fatal:
+		log.Printf("Fatal: %v", err)
+		exitCode = 1
+	}
 
 	log.Println("Shutting down server...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+
+	// Stop accepting new connections and wait for in-flight requests
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("Error shutting down server: %v", err)
+	}
+
+	// Stop polling and let the buffered updates and running broadcasts finish
+	if h := handler.Load(); h != nil {
+		if err := h.Stop(ctx); err != nil {
+			log.Printf("Error stopping bot: %v", err)
+		}
+	}
+
+	log.Println("Server stopped")
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
+}
+
+// parseIDs parses a comma-separated list of Telegram user IDs, skipping invalid entries
+func parseIDs(value string) []int64 {
+	var ids []int64
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			log.Printf("Ignoring invalid user ID %q", field)
+			continue
+		}
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+// envInt reads an integer environment variable, returning 0 if unset or invalid
+func envInt(name string) int64 {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		log.Printf("Ignoring invalid %s %q", name, value)
+		return 0
+	}
+
+	return n
+}
+
+// envBool reads a boolean environment variable, returning false if unset or invalid
+func envBool(name string) bool {
+	value := os.Getenv(name)
+	if value == "" {
+		return false
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Ignoring invalid %s %q", name, value)
+		return false
+	}
+
+	return b
+}
+
+// envDuration reads a duration environment variable such as "15s", returning 0 if unset or invalid
+func envDuration(name string) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Ignoring invalid %s %q", name, value)
+		return 0
+	}
+
+	return d
 }
 
 // handleRoot handles the root endpoint
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// errBotNotReady is returned when the profile is synced before the bot is connected
+var errBotNotReady = errors.New("bot not connected")
+
+// botCommands lists the public commands with the translation keys of their descriptions
+var botCommands = []struct {
+	Command string
+	Key     string
+}{
+	{Command: "start", Key: "command.start.description"},
+}
+
+// profileSync pushes localized bot descriptions and command lists to Telegram
+type profileSync struct {
+	tr *translations
+
+	mu  sync.Mutex
+	api *tgbotapi.BotAPI
+}
+
+// newProfileSync creates a profile sync using the bundles in tr
+func newProfileSync(tr *translations) *profileSync {
+	return &profileSync{tr: tr}
+}
+
+// SetBot sets the bot whose profile is synced
+func (p *profileSync) SetBot(api *tgbotapi.BotAPI) {
+	p.mu.Lock()
+	p.api = api
+	p.mu.Unlock()
+}
+
+// telegramLanguages maps Telegram language codes to the locales pushed
+// under them. Telegram only accepts two-letter ISO 639-1 codes, so region
+// variants are pushed under their base language unless the base locale
+// exists itself, and other locales are skipped.
+func telegramLanguages(locales []string) map[string]string {
+	languages := make(map[string]string)
+	for _, locale := range locales {
+		if len(locale) == 2 {
+			languages[locale] = locale
+		}
+	}
+
+	for _, locale := range locales {
+		if len(locale) == 2 {
+			continue
+		}
+		base, _, _ := strings.Cut(locale, "-")
+		if len(base) != 2 {
+			log.Printf("Skipping profile sync for %s: Telegram only accepts two-letter language codes", locale)
+			continue
+		}
+		if other, ok := languages[base]; ok {
+			log.Printf("Skipping profile sync for %s: %s is already pushed as %s", locale, other, base)
+			continue
+		}
+		languages[base] = locale
+	}
+
+	return languages
+}
+
+// Sync pushes the description, short description and commands of every
+// locale Telegram accepts; the default locale is also pushed without a
+// language code so it applies to users whose language has no translation
+func (p *profileSync) Sync() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.api == nil {
+		return errBotNotReady
+	}
+
+	var errs []error
+	for languageCode, locale := range telegramLanguages(p.tr.Locales()) {
+		if err := p.syncLocale(locale, languageCode); err != nil {
+			errs = append(errs, err)
+		}
+		if locale == defaultLocale {
+			if err := p.syncLocale(locale, ""); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	log.Println("Synced bot profile with Telegram")
+	return nil
+}
+
+// syncLocale pushes the bundle of locale under languageCode, skipping
+// missing keys so Telegram falls back to the default profile
+func (p *profileSync) syncLocale(locale, languageCode string) error {
+	methods := []struct {
+		Method string
+		Field  string
+		Key    string
+	}{
+		{Method: "setMyDescription", Field: "description", Key: "bot.description"},
+		{Method: "setMyShortDescription", Field: "short_description", Key: "bot.short_description"},
+	}
+	for _, m := range methods {
+		value, ok := p.tr.Lookup(locale, m.Key)
+		if !ok {
+			continue
+		}
+
+		params := make(tgbotapi.Params)
+		params.AddNonEmpty(m.Field, value)
+		params.AddNonEmpty("language_code", languageCode)
+		if _, err := p.api.MakeRequest(m.Method, params); err != nil {
+			return fmt.Errorf("%s for %s: %v", m.Method, locale, err)
+		}
+	}
+
+	var commands []tgbotapi.BotCommand
+	for _, c := range botCommands {
+		if description, ok := p.tr.Lookup(locale, c.Key); ok {
+			commands = append(commands, tgbotapi.BotCommand{Command: c.Command, Description: description})
+		}
+	}
+	if len(commands) == 0 {
+		return nil
+	}
+
+	config := tgbotapi.NewSetMyCommands(commands...)
+	config.LanguageCode = languageCode
+	if _, err := p.api.Request(config); err != nil {
+		return fmt.Errorf("setMyCommands for %s: %v", locale, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"runtime/debug"
+)
+
+// logPanic logs a recovered panic value with its stack trace
+func logPanic(where string, p any) {
+	log.Printf("Panic in %s: %v\n%s", where, p, debug.Stack())
+}
+
+// recoverHandler recovers from panics in next, logging them and responding with 500
+func recoverHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if p := recover(); p != nil {
+				// ErrAbortHandler is the documented way to abort a response, let net/http handle it
+				if p == http.ErrAbortHandler {
+					panic(p)
+				}
+				logPanic(r.Method+" "+r.URL.Path, p)
+				writeError(w, http.StatusInternalServerError, "internal error")
+			}
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"html"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// CustomEmojiConfig controls whether bot messages use custom emoji entities
+type CustomEmojiConfig struct {
+	Enabled bool              `yaml:"enabled"`
+	IDs     map[string]string `yaml:"ids"`
+}
+
+// fallbackEmoji maps emoji names to the plain emoji used when custom emoji are unavailable
+var fallbackEmoji = map[string]string{
+	"game":   "🎮",
+	"trophy": "🏆",
+}
+
+// customEmojiFeature is the feature flag toggling custom emoji at runtime
+const customEmojiFeature = "custom_emoji"
+
+// renderer builds HTML message text, rendering named emoji as custom emoji
+// entities when enabled for the deployment and as plain emoji otherwise
+type renderer struct {
+	cfg   CustomEmojiConfig
+	flags *featureFlags
+}
+
+// newRenderer creates a renderer for the given configuration; custom emoji
+// are used while the custom_emoji feature flag (seeded from cfg.Enabled) is on
+func newRenderer(cfg CustomEmojiConfig, flags *featureFlags) *renderer {
+	return &renderer{cfg: cfg, flags: flags}
+}
+
+// Emoji renders the named emoji
+func (r *renderer) Emoji(name string) string {
+	fallback := fallbackEmoji[name]
+	id, ok := r.cfg.IDs[name]
+	if !r.flags.Enabled(customEmojiFeature) || !ok || id == "" || fallback == "" {
+		return fallback
+	}
+
+	return fmt.Sprintf(`<tg-emoji emoji-id="%s">%s</tg-emoji>`, html.EscapeString(id), fallback)
+}
+
+// Text renders plain text prefixed with the named emoji
+func (r *renderer) Text(emoji, text string) string {
+	if e := r.Emoji(emoji); e != "" {
+		return e + " " + html.EscapeString(text)
+	}
+
+	return html.EscapeString(text)
+}
+
+// NewMessage creates a message to chatID with text produced by this renderer
+func (r *renderer) NewMessage(chatID int64, text string) tgbotapi.MessageConfig {
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = tgbotapi.ModeHTML
+	return msg
+}
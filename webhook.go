@@ -0,0 +1,260 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// maxWebhookSize limits the size of build webhook payloads
+	maxWebhookSize = 64 << 10
+	// buildWebhookTolerance is how far a webhook timestamp may be from the server clock
+	buildWebhookTolerance = 5 * time.Minute
+)
+
+// BuildWebhookConfig configures the frontend CI build webhook
+type BuildWebhookConfig struct {
+	Secret         string `yaml:"secret"`
+	AnnounceChatID int64  `yaml:"announce_chat_id"`
+	StateFile      string `yaml:"state_file"`
+}
+
+// buildState is the last applied build, persisted so it survives restarts
+type buildState struct {
+	URL       string    `json:"url"`
+	Version   string    `json:"version"`
+	Timestamp int64     `json:"timestamp"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// buildEvent is the payload CI sends when a new game build is published
+type buildEvent struct {
+	Game    string `json:"game"`
+	URL     string `json:"url"`
+	Version string `json:"version"`
+}
+
+// buildWebhook points the game link at newly published frontend builds
+type buildWebhook struct {
+	cfg       BuildWebhookConfig
+	shortName string
+	link      *gameLink
+
+	mu          sync.Mutex
+	api         *tgbotapi.BotAPI
+	deliveries  map[string]time.Time
+	lastApplied int64
+}
+
+// newBuildWebhook creates a build webhook updating link for the game
+// shortName, restoring the last applied build from the state file if any
+func newBuildWebhook(cfg BuildWebhookConfig, shortName string, link *gameLink) (*buildWebhook, error) {
+	b := &buildWebhook{
+		cfg:        cfg,
+		shortName:  shortName,
+		link:       link,
+		deliveries: make(map[string]time.Time),
+	}
+	if cfg.StateFile == "" {
+		return b, nil
+	}
+
+	data, err := os.ReadFile(cfg.StateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return b, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading build state: %v", err)
+	}
+	var state buildState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("error parsing build state %s: %v", cfg.StateFile, err)
+	}
+
+	if configured := link.URL(); state.URL != configured {
+		log.Printf("Warning: game_url %s differs from build %q applied at %s, using %s", configured, state.Version, state.AppliedAt.Format(time.RFC3339), state.URL)
+	}
+	link.SetURL(state.URL)
+	b.lastApplied = state.Timestamp
+
+	return b, nil
+}
+
+// SetBot sets the bot used to announce new builds
+func (b *buildWebhook) SetBot(api *tgbotapi.BotAPI) {
+	b.mu.Lock()
+	b.api = api
+	b.mu.Unlock()
+}
+
+// handleBuild verifies the X-Signature header and switches the game to the
+// new build. The signature is the hex HMAC-SHA256, optionally prefixed with
+// "sha256=", of "<X-Timestamp>.<X-Delivery-ID>.<body>"; stale timestamps,
+// repeated delivery IDs and events older than the last applied one are rejected.
+func (b *buildWebhook) handleBuild(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookSize))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, "payload too large")
+		return
+	}
+
+	timestamp := r.Header.Get("X-Timestamp")
+	deliveryID := r.Header.Get("X-Delivery-ID")
+	// The signed string is joined with dots, so IDs containing one could shift bytes between fields
+	if deliveryID == "" || strings.Contains(deliveryID, ".") || !b.validSignature(timestamp, deliveryID, body, r.Header.Get("X-Signature")) {
+		writeError(w, http.StatusUnauthorized, "invalid signature")
+		return
+	}
+	sent, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid timestamp")
+		return
+	}
+	if age := time.Since(time.Unix(sent, 0)); age > buildWebhookTolerance || age < -buildWebhookTolerance {
+		writeError(w, http.StatusUnauthorized, "stale timestamp")
+		return
+	}
+
+	var event buildEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid payload: "+err.Error())
+		return
+	}
+	if event.Game == "" || event.Game != b.shortName {
+		writeError(w, http.StatusNotFound, "unknown game")
+		return
+	}
+	u, err := url.Parse(event.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		writeError(w, http.StatusBadRequest, "invalid url")
+		return
+	}
+
+	previous, err := b.apply(deliveryID, sent, event)
+	if err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+
+	log.Printf("Game %s switched to build %q at %s (was %s)", event.Game, event.Version, event.URL, previous)
+	go b.announce(event)
+
+	writeJSON(w, http.StatusOK, map[string]string{"game": event.Game, "url": event.URL})
+}
+
+// apply records a delivery and switches the game to its build, returning the
+// previous URL. Repeated delivery IDs and events sent before the last applied
+// one are rejected so an old build cannot be rolled back to.
+func (b *buildWebhook) apply(deliveryID string, sent int64, event buildEvent) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// Delivery IDs only need to be kept while their timestamp is still fresh
+	now := time.Now()
+	for id, seen := range b.deliveries {
+		if now.Sub(seen) > 2*buildWebhookTolerance {
+			delete(b.deliveries, id)
+		}
+	}
+
+	if _, ok := b.deliveries[deliveryID]; ok {
+		return "", fmt.Errorf("duplicate delivery")
+	}
+	if sent < b.lastApplied {
+		return "", fmt.Errorf("event older than the current build")
+	}
+
+	b.deliveries[deliveryID] = now
+	b.lastApplied = sent
+
+	// Saved while holding mu so the file always matches the latest event
+	state := buildState{URL: event.URL, Version: event.Version, Timestamp: sent, AppliedAt: now}
+	if err := b.saveState(state); err != nil {
+		log.Printf("Error saving build state: %v", err)
+	}
+
+	previous := b.link.URL()
+	b.link.SetURL(event.URL)
+	return previous, nil
+}
+
+// saveState writes state to the state file, if configured
+func (b *buildWebhook) saveState(state buildState) error {
+	if b.cfg.StateFile == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("error encoding build state: %v", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves a partial state file
+	tmp, err := os.CreateTemp(filepath.Dir(b.cfg.StateFile), filepath.Base(b.cfg.StateFile)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error writing build state: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing build state: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing build state: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), b.cfg.StateFile); err != nil {
+		return fmt.Errorf("error writing build state: %v", err)
+	}
+
+	return nil
+}
+
+// validSignature reports whether signature is the HMAC of the timestamp,
+// delivery ID and body with the webhook secret
+func (b *buildWebhook) validSignature(timestamp, deliveryID string, body []byte, signature string) bool {
+	got, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(b.cfg.Secret))
+	mac.Write([]byte(timestamp + "." + deliveryID + "."))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// announce posts the new build to the configured admin chat
+func (b *buildWebhook) announce(event buildEvent) {
+	b.mu.Lock()
+	api := b.api
+	b.mu.Unlock()
+
+	if b.cfg.AnnounceChatID == 0 || api == nil {
+		return
+	}
+
+	text := fmt.Sprintf("New build of %s published: %s", event.Game, event.URL)
+	if event.Version != "" {
+		text = fmt.Sprintf("New build %s of %s published: %s", event.Version, event.Game, event.URL)
+	}
+	if _, err := api.Send(tgbotapi.NewMessage(b.cfg.AnnounceChatID, text)); err != nil {
+		log.Printf("Error announcing build: %v", err)
+	}
+}
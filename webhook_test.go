@@ -0,0 +1,153 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testWebhookSecret = "secret"
+
+// buildRequest builds a build webhook request signed over signedBody, sending body instead if set
+func buildRequest(sent time.Time, deliveryID, signedBody, body string) *http.Request {
+	timestamp := strconv.FormatInt(sent.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(testWebhookSecret))
+	mac.Write([]byte(timestamp + "." + deliveryID + "." + signedBody))
+
+	if body == "" {
+		body = signedBody
+	}
+	r := httptest.NewRequest(http.MethodPost, "/webhooks/build", strings.NewReader(body))
+	r.Header.Set("X-Timestamp", timestamp)
+	r.Header.Set("X-Delivery-ID", deliveryID)
+	r.Header.Set("X-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	return r
+}
+
+// newTestBuildWebhook creates a build webhook for the game "g" without a state file
+func newTestBuildWebhook(t *testing.T) (*buildWebhook, *gameLink) {
+	t.Helper()
+
+	link := newGameLink("https://example.com/v1/")
+	b, err := newBuildWebhook(BuildWebhookConfig{Secret: testWebhookSecret}, "g", link)
+	if err != nil {
+		t.Fatalf("newBuildWebhook: %v", err)
+	}
+	return b, link
+}
+
+func TestHandleBuild(t *testing.T) {
+	const v2 = `{"game": "g", "url": "https://example.com/v2/", "version": "v2"}`
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		requests []*http.Request
+		status   int
+		url      string
+	}{
+		{
+			name:     "valid signature",
+			requests: []*http.Request{buildRequest(now, "1", v2, "")},
+			status:   http.StatusOK,
+			url:      "https://example.com/v2/",
+		},
+		{
+			name:     "tampered body",
+			requests: []*http.Request{buildRequest(now, "1", v2, `{"game": "g", "url": "https://evil.example/", "version": "v2"}`)},
+			status:   http.StatusUnauthorized,
+			url:      "https://example.com/v1/",
+		},
+		{
+			name:     "stale timestamp",
+			requests: []*http.Request{buildRequest(now.Add(-2*buildWebhookTolerance), "1", v2, "")},
+			status:   http.StatusUnauthorized,
+			url:      "https://example.com/v1/",
+		},
+		{
+			name:     "delivery id with a dot",
+			requests: []*http.Request{buildRequest(now, "1.2", v2, "")},
+			status:   http.StatusUnauthorized,
+			url:      "https://example.com/v1/",
+		},
+		{
+			name:     "empty game",
+			requests: []*http.Request{buildRequest(now, "1", `{"game": "", "url": "https://example.com/v2/"}`, "")},
+			status:   http.StatusNotFound,
+			url:      "https://example.com/v1/",
+		},
+		{
+			name: "replayed delivery id",
+			requests: []*http.Request{
+				buildRequest(now, "1", v2, ""),
+				buildRequest(now, "1", `{"game": "g", "url": "https://example.com/v3/"}`, ""),
+			},
+			status: http.StatusConflict,
+			url:    "https://example.com/v2/",
+		},
+		{
+			name: "older timestamp",
+			requests: []*http.Request{
+				buildRequest(now, "2", v2, ""),
+				buildRequest(now.Add(-time.Minute), "1", `{"game": "g", "url": "https://example.com/v1/"}`, ""),
+			},
+			status: http.StatusConflict,
+			url:    "https://example.com/v2/",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, link := newTestBuildWebhook(t)
+
+			var w *httptest.ResponseRecorder
+			for _, r := range tt.requests {
+				w = httptest.NewRecorder()
+				b.handleBuild(w, r)
+			}
+
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d (%s)", w.Code, tt.status, strings.TrimSpace(w.Body.String()))
+			}
+			if got := link.URL(); got != tt.url {
+				t.Errorf("game URL = %s, want %s", got, tt.url)
+			}
+		})
+	}
+}
+
+func TestBuildWebhookState(t *testing.T) {
+	cfg := BuildWebhookConfig{Secret: testWebhookSecret, StateFile: t.TempDir() + "/build.json"}
+	now := time.Now()
+
+	b, err := newBuildWebhook(cfg, "g", newGameLink("https://example.com/v1/"))
+	if err != nil {
+		t.Fatalf("newBuildWebhook: %v", err)
+	}
+	w := httptest.NewRecorder()
+	b.handleBuild(w, buildRequest(now, "1", `{"game": "g", "url": "https://example.com/v2/"}`, ""))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	// A restarted server restores the build and still rejects older events
+	link := newGameLink("https://example.com/v1/")
+	b, err = newBuildWebhook(cfg, "g", link)
+	if err != nil {
+		t.Fatalf("newBuildWebhook after restart: %v", err)
+	}
+	if got := link.URL(); got != "https://example.com/v2/" {
+		t.Errorf("restored game URL = %s, want https://example.com/v2/", got)
+	}
+	w = httptest.NewRecorder()
+	b.handleBuild(w, buildRequest(now.Add(-time.Minute), "2", `{"game": "g", "url": "https://example.com/v1/"}`, ""))
+	if w.Code != http.StatusConflict {
+		t.Errorf("older event after restart: status = %d, want %d", w.Code, http.StatusConflict)
+	}
+}